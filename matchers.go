@@ -0,0 +1,372 @@
+package router
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// queryMatcher constrains a single query string parameter. When regex is
+// nil the parameter must equal value exactly; otherwise the parameter's
+// value must match the (possibly named) regex
+type queryMatcher struct {
+	key   string
+	value string
+	regex *regexp.Regexp
+}
+
+// templateVar matches a gorilla/mux-style "{name}" or "{name:pattern}"
+// placeholder, used by Host() and Queries()
+var templateVar = regexp.MustCompile(`\{([a-zA-Z0-9_]+)(?::([^{}]+))?\}`)
+
+// compileTemplate turns a "{name}" / "{name:pattern}" template into an
+// anchored regex with named capture groups. Literal characters are escaped;
+// pattern defaults to defaultPattern when a variable omits one
+func compileTemplate(tmpl string, defaultPattern string) *regexp.Regexp {
+	var out strings.Builder
+	out.WriteString("^")
+
+	last := 0
+	for _, loc := range templateVar.FindAllStringSubmatchIndex(tmpl, -1) {
+		out.WriteString(regexp.QuoteMeta(tmpl[last:loc[0]]))
+
+		name := tmpl[loc[2]:loc[3]]
+		pattern := defaultPattern
+		if loc[4] != -1 {
+			pattern = tmpl[loc[4]:loc[5]]
+		}
+
+		out.WriteString("(?P<" + name + ">" + pattern + ")")
+		last = loc[1]
+	}
+
+	out.WriteString(regexp.QuoteMeta(tmpl[last:]))
+	out.WriteString("$")
+
+	return regexp.MustCompile(out.String())
+}
+
+// Host sets a host constraint on the route. tmpl may contain "{name}" or
+// "{name:pattern}" variables (e.g. "{sub:[a-z]+}.example.com"), which are
+// merged into the params map on a match and substituted by URL
+func (route *Route) Host(tmpl string) *Route {
+	route.hostTemplate = tmpl
+	route.hostRegex = compileTemplate(tmpl, "[^.]+")
+
+	return route
+}
+
+// Schemes restricts the route to the given URL schemes (e.g. "https")
+func (route *Route) Schemes(schemes ...string) *Route {
+	route.schemes = route.schemes[:0]
+
+	for _, scheme := range schemes {
+		route.schemes = append(route.schemes, strings.ToLower(scheme))
+	}
+
+	return route
+}
+
+// Headers restricts the route to requests carrying the given header/value
+// pairs, e.g. Headers("X-Requested-With", "XMLHttpRequest")
+func (route *Route) Headers(pairs ...string) *Route {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		route.headers = append(route.headers, [2]string{pairs[i], pairs[i+1]})
+	}
+
+	return route
+}
+
+// Queries restricts the route to requests whose query string carries the
+// given key/value pairs. A value may be a literal ("1") or a "{name}" /
+// "{name:pattern}" template, in which case the captured value is merged
+// into the params map
+func (route *Route) Queries(pairs ...string) *Route {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		key := pairs[i]
+		value := pairs[i+1]
+
+		if templateVar.MatchString(value) {
+			route.queries = append(route.queries, queryMatcher{key: key, regex: compileTemplate(value, "[^&]+")})
+			continue
+		}
+
+		route.queries = append(route.queries, queryMatcher{key: key, value: value})
+	}
+
+	return route
+}
+
+// Subrouter returns a Router for registering routes nested under this
+// route's path, inheriting its host/scheme/header/query constraints and
+// middleware so they don't need to be re-declared on every child route.
+// route itself stays registered and dispatchable, so a request that only
+// matches route's own pattern (e.g. a bare "/api/" when route is
+// "/api/") will still be served by it, empty Handlers and all; use
+// Router.PathPrefix/Host/Schemes/Headers/Queries instead when a
+// non-dispatchable mount point is what's wanted
+func (route *Route) Subrouter() *Router {
+	sub := route.router.Subrouter()
+
+	sub.prefix = strings.TrimRight(route.rawPattern, "/")
+	sub.namePrefix = route.Name
+	sub.hostRegex = route.hostRegex
+	sub.hostTemplate = route.hostTemplate
+	sub.schemes = append([]string(nil), route.schemes...)
+	sub.headers = append([][2]string(nil), route.headers...)
+	sub.queries = append([]queryMatcher(nil), route.queries...)
+	sub.middleware = append([]func(http.Handler) http.Handler(nil), route.middleware...)
+	sub.mountAncestors = append(append([]*Route(nil), route.router.mountAncestors...), route)
+
+	return sub
+}
+
+// PathPrefix appends prefix to this Router's own prefix, the Router-level
+// equivalent of Route.Subrouter's path inheritance for mounting routes
+// under a shared prefix without a dispatchable parent route. Typically
+// called on a Subrouter() so the prefix doesn't leak onto r's own routes
+func (r *Router) PathPrefix(prefix string) *Router {
+	r.prefix += strings.TrimRight(prefix, "/")
+
+	return r
+}
+
+// Host sets a host constraint inherited by every route registered through
+// this Router (or a Subrouter/Group of it), the Router-level equivalent of
+// Route.Host for mounting routes under a shared host without a
+// dispatchable parent route
+func (r *Router) Host(tmpl string) *Router {
+	r.hostTemplate = tmpl
+	r.hostRegex = compileTemplate(tmpl, "[^.]+")
+
+	return r
+}
+
+// Schemes restricts every route registered through this Router to the
+// given URL schemes, the Router-level equivalent of Route.Schemes
+func (r *Router) Schemes(schemes ...string) *Router {
+	r.schemes = r.schemes[:0]
+
+	for _, scheme := range schemes {
+		r.schemes = append(r.schemes, strings.ToLower(scheme))
+	}
+
+	return r
+}
+
+// Headers restricts every route registered through this Router to requests
+// carrying the given header/value pairs, the Router-level equivalent of
+// Route.Headers
+func (r *Router) Headers(pairs ...string) *Router {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		r.headers = append(r.headers, [2]string{pairs[i], pairs[i+1]})
+	}
+
+	return r
+}
+
+// Queries restricts every route registered through this Router to requests
+// whose query string carries the given key/value pairs, the Router-level
+// equivalent of Route.Queries
+func (r *Router) Queries(pairs ...string) *Router {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		key := pairs[i]
+		value := pairs[i+1]
+
+		if templateVar.MatchString(value) {
+			r.queries = append(r.queries, queryMatcher{key: key, regex: compileTemplate(value, "[^&]+")})
+			continue
+		}
+
+		r.queries = append(r.queries, queryMatcher{key: key, value: value})
+	}
+
+	return r
+}
+
+// Method returns the HTTP method the route is registered for
+func (route *Route) Method() string {
+	return route.method
+}
+
+// Pattern returns the route's compiled regex pattern with named capture
+// groups, as used before Dispatch moved to radix tree matching
+func (route *Route) Pattern() string {
+	return route.pattern
+}
+
+// BasePattern returns the route's pattern with type/regex annotations
+// stripped down to plain ":name" placeholders (e.g. "/users/:id" rather
+// than the compiled "/users/(?P<id>[0-9]+)"), the form a Walk consumer
+// generating route templates (e.g. for OpenAPI) would want
+func (route *Route) BasePattern() string {
+	return route.basePattern
+}
+
+// matchesRequest checks the route's scheme/host/header/query constraints
+// against req, merging any captured host/query variables into params
+func (route *Route) matchesRequest(req *http.Request, params map[string]interface{}) bool {
+	if len(route.schemes) > 0 {
+		scheme := strings.ToLower(req.URL.Scheme)
+		if scheme == "" {
+			// net/http never populates URL.Scheme on a server-side request,
+			// even when the connection came in over TLS, so fall back to
+			// req.TLS to tell https from http
+			if req.TLS != nil {
+				scheme = "https"
+			} else {
+				scheme = "http"
+			}
+		}
+
+		matched := false
+		for _, allowed := range route.schemes {
+			if allowed == scheme {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	if route.hostRegex != nil {
+		host := req.Host
+		if idx := strings.Index(host, ":"); idx != -1 {
+			host = host[:idx]
+		}
+
+		match := route.hostRegex.FindStringSubmatch(host)
+		if match == nil {
+			return false
+		}
+
+		for i, name := range route.hostRegex.SubexpNames() {
+			if i == 0 || name == "" {
+				continue
+			}
+			params[name] = match[i]
+		}
+	}
+
+	for _, header := range route.headers {
+		if req.Header.Get(header[0]) != header[1] {
+			return false
+		}
+	}
+
+	if len(route.queries) > 0 {
+		values := req.URL.Query()
+
+		for _, q := range route.queries {
+			actual := values.Get(q.key)
+
+			if q.regex == nil {
+				if actual != q.value {
+					return false
+				}
+				continue
+			}
+
+			match := q.regex.FindStringSubmatch(actual)
+			if match == nil {
+				return false
+			}
+
+			for i, name := range q.regex.SubexpNames() {
+				if i == 0 || name == "" {
+					continue
+				}
+				params[name] = match[i]
+			}
+		}
+	}
+
+	return true
+}
+
+// paramValues flattens the name/value varargs accepted by Route.URL into a
+// map, the same way the "(name, value, name, value...)" pairs have always
+// been interpreted
+func paramValues(params []interface{}) map[string]string {
+	values := make(map[string]string, len(params)/2)
+
+	for key, value := range params {
+		if key%2 == 0 {
+			continue
+		}
+
+		name := params[key-1].(string)
+
+		switch v := value.(type) {
+		case int:
+			values[name] = strconv.Itoa(v)
+		case bool:
+			values[name] = strconv.FormatBool(v)
+		case string:
+			values[name] = v
+		}
+	}
+
+	return values
+}
+
+// substituteColon replaces ":name" placeholders in tmpl (as used by
+// basePattern) with the matching value from params
+func substituteColon(tmpl string, params []interface{}) string {
+	out := tmpl
+
+	for name, value := range paramValues(params) {
+		out = strings.Replace(out, ":"+name, value, -1)
+	}
+
+	return out
+}
+
+// substituteCurly replaces "{name}" / "{name:pattern}" placeholders in tmpl
+// (as used by hostTemplate) with the matching value from params
+func substituteCurly(tmpl string, params []interface{}) string {
+	values := paramValues(params)
+
+	return templateVar.ReplaceAllStringFunc(tmpl, func(match string) string {
+		sub := templateVar.FindStringSubmatch(match)
+		if value, ok := values[sub[1]]; ok {
+			return value
+		}
+
+		return match
+	})
+}
+
+// buildQuery reconstructs the query string for a route's Queries()
+// constraints from the given URL params
+func buildQuery(queries []queryMatcher, params []interface{}) string {
+	if len(queries) == 0 {
+		return ""
+	}
+
+	values := paramValues(params)
+	query := url.Values{}
+
+	for _, q := range queries {
+		if q.regex == nil {
+			query.Set(q.key, q.value)
+			continue
+		}
+
+		for _, name := range q.regex.SubexpNames() {
+			if name == "" {
+				continue
+			}
+			if value, ok := values[name]; ok {
+				query.Set(q.key, value)
+			}
+		}
+	}
+
+	return query.Encode()
+}
@@ -0,0 +1,79 @@
+package router
+
+import (
+	"crypto/tls"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostMatcher(t *testing.T) {
+	r := New()
+	r.Get("tenant", "/dashboard").Host("{tenant:[a-z]+}.example.com")
+
+	req := httptest.NewRequest("GET", "/dashboard", nil)
+	req.Host = "acme.example.com"
+
+	route, params, _ := r.Dispatch(req)
+	if route == nil || route.Name != "tenant" {
+		t.Fatalf("expected host match, got %v", route)
+	}
+	if params["tenant"] != "acme" {
+		t.Fatalf("expected tenant=acme, got %#v", params["tenant"])
+	}
+
+	req = httptest.NewRequest("GET", "/dashboard", nil)
+	req.Host = "123.example.com"
+	if route, _, _ := r.Dispatch(req); route != nil {
+		t.Fatalf("expected no match for a host that fails the template pattern, got %v", route)
+	}
+}
+
+func TestSchemeMatcher(t *testing.T) {
+	r := New()
+	r.Get("secure", "/secure").Schemes("https")
+
+	plain := httptest.NewRequest("GET", "/secure", nil)
+	if route, _, _ := r.Dispatch(plain); route != nil {
+		t.Fatalf("expected no match over plain http, got %v", route)
+	}
+
+	tlsReq := httptest.NewRequest("GET", "/secure", nil)
+	tlsReq.TLS = &tls.ConnectionState{}
+	if route, _, _ := r.Dispatch(tlsReq); route == nil || route.Name != "secure" {
+		t.Fatalf("expected https match via req.TLS, got %v", route)
+	}
+}
+
+func TestHeaderMatcher(t *testing.T) {
+	r := New()
+	r.Get("ajax", "/widget").Headers("X-Requested-With", "XMLHttpRequest")
+
+	req := httptest.NewRequest("GET", "/widget", nil)
+	if route, _, _ := r.Dispatch(req); route != nil {
+		t.Fatalf("expected no match without the header, got %v", route)
+	}
+
+	req.Header.Set("X-Requested-With", "XMLHttpRequest")
+	if route, _, _ := r.Dispatch(req); route == nil || route.Name != "ajax" {
+		t.Fatalf("expected header match, got %v", route)
+	}
+}
+
+func TestQueryMatcher(t *testing.T) {
+	r := New()
+	r.Get("search", "/search").Queries("q", "{term}", "page", "1")
+
+	req := httptest.NewRequest("GET", "/search?q=shoes&page=2", nil)
+	if route, _, _ := r.Dispatch(req); route != nil {
+		t.Fatalf("expected no match when a literal query value doesn't match, got %v", route)
+	}
+
+	req = httptest.NewRequest("GET", "/search?q=shoes&page=1", nil)
+	route, params, _ := r.Dispatch(req)
+	if route == nil || route.Name != "search" {
+		t.Fatalf("expected query match, got %v", route)
+	}
+	if params["term"] != "shoes" {
+		t.Fatalf("expected term=shoes, got %#v", params["term"])
+	}
+}
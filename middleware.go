@@ -0,0 +1,72 @@
+package router
+
+import (
+	"context"
+	"net/http"
+)
+
+// contextKey is an unexported type to avoid collisions with context keys
+// set by other packages
+type contextKey int
+
+// varsContextKey is the key under which Dispatch's captured parameters are
+// stored on the request context
+const varsContextKey contextKey = iota
+
+// newVarsContext returns a context carrying vars so Vars(r) can retrieve it
+func newVarsContext(ctx context.Context, vars map[string]interface{}) context.Context {
+	return context.WithValue(ctx, varsContextKey, vars)
+}
+
+// Vars returns the parameters Dispatch captured for the matched route (path
+// params plus any host/query/ext values), or an empty map if req was never
+// served through a Router
+func Vars(r *http.Request) map[string]interface{} {
+	if vars, ok := r.Context().Value(varsContextKey).(map[string]interface{}); ok {
+		return vars
+	}
+
+	return map[string]interface{}{}
+}
+
+// Use appends per-route middleware, composed in registration order around
+// the route's Handlers
+func (route *Route) Use(mw ...func(http.Handler) http.Handler) *Route {
+	route.compileMu.Lock()
+	defer route.compileMu.Unlock()
+
+	route.middleware = append(route.middleware, mw...)
+	route.compiled = nil
+
+	return route
+}
+
+// handler returns the route's middleware-wrapped handler chain, building
+// and caching it on first use. route.middleware already holds the full
+// inherited chain (router/subrouter middleware seeded at registration time,
+// plus anything added via Route.Use), composed in order around Handlers.
+// Guarded by compileMu since ServeHTTP calls this from whatever goroutine
+// net/http is using for the connection, which means concurrently for the
+// same route under normal server load
+func (route *Route) handler() http.Handler {
+	route.compileMu.Lock()
+	defer route.compileMu.Unlock()
+
+	if route.compiled != nil {
+		return route.compiled
+	}
+
+	var final http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, h := range route.Handlers {
+			h(w, r)
+		}
+	})
+
+	for i := len(route.middleware) - 1; i >= 0; i-- {
+		final = route.middleware[i](final)
+	}
+
+	route.compiled = final
+
+	return final
+}
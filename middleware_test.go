@@ -0,0 +1,87 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// tagMiddleware appends tag to an "order" slice on its way in, so tests can
+// assert the composition order of Use/Route.Use/Group's middleware
+func tagMiddleware(order *[]string, tag string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*order = append(*order, tag)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestUseComposesGlobalAndPerRouteMiddlewareInOrder(t *testing.T) {
+	var order []string
+
+	r := New()
+	r.Use(tagMiddleware(&order, "global1"), tagMiddleware(&order, "global2"))
+	r.Get("ping", "/ping", func(w http.ResponseWriter, req *http.Request) {
+		order = append(order, "handler")
+	}).Use(tagMiddleware(&order, "route1"), tagMiddleware(&order, "route2"))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ping", nil))
+
+	expected := []string{"global1", "global2", "route1", "route2", "handler"}
+	if len(order) != len(expected) {
+		t.Fatalf("expected order %v, got %v", expected, order)
+	}
+	for i, tag := range expected {
+		if order[i] != tag {
+			t.Fatalf("expected order %v, got %v", expected, order)
+		}
+	}
+}
+
+func TestUseDoesNotAffectRoutesRegisteredBeforeIt(t *testing.T) {
+	var order []string
+
+	r := New()
+	r.Get("early", "/early", func(w http.ResponseWriter, req *http.Request) {
+		order = append(order, "handler")
+	})
+	r.Use(tagMiddleware(&order, "late"))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/early", nil))
+
+	if len(order) != 1 || order[0] != "handler" {
+		t.Fatalf("expected middleware registered after the route to be skipped, got %v", order)
+	}
+}
+
+func TestGroupMiddlewareAppliesOnTopOfParent(t *testing.T) {
+	var order []string
+
+	r := New()
+	r.Use(tagMiddleware(&order, "parent"))
+	r.Group("/admin", "admin", func(sub *Router) {
+		sub.Get("dashboard", "/dashboard", func(w http.ResponseWriter, req *http.Request) {
+			order = append(order, "handler")
+		})
+	}, tagMiddleware(&order, "group"))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/admin/dashboard", nil))
+
+	expected := []string{"parent", "group", "handler"}
+	if len(order) != len(expected) {
+		t.Fatalf("expected order %v, got %v", expected, order)
+	}
+	for i, tag := range expected {
+		if order[i] != tag {
+			t.Fatalf("expected order %v, got %v", expected, order)
+		}
+	}
+}
+
+func TestVarsReturnsEmptyMapForUnservedRequest(t *testing.T) {
+	vars := Vars(httptest.NewRequest("GET", "/whatever", nil))
+	if len(vars) != 0 {
+		t.Fatalf("expected an empty map, got %#v", vars)
+	}
+}
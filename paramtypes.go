@@ -0,0 +1,61 @@
+package router
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// ParamConverter turns a matched path segment into the Go value stored in
+// the params map for that parameter
+type ParamConverter func(string) (interface{}, error)
+
+// paramType is a registered named parameter type: the regex a segment must
+// match plus the converter used to turn it into a Go value
+type paramType struct {
+	pattern   string
+	regex     *regexp.Regexp
+	converter ParamConverter
+}
+
+// stringConverter passes the matched segment through unchanged
+func stringConverter(value string) (interface{}, error) {
+	return value, nil
+}
+
+// registerBuiltinParamTypes seeds reg with the convenience placeholders
+// that have always been supported inline in route patterns, plus uuid and
+// bool
+func registerBuiltinParamTypes(reg *registry) {
+	reg.paramTypes = make(map[string]paramType)
+
+	reg.registerParamType("int", "[0-9]+", func(value string) (interface{}, error) {
+		return strconv.Atoi(value)
+	})
+	reg.registerParamType("alpha", "[a-z]+", stringConverter)
+	reg.registerParamType("alphanumeric", "[a-z0-9]+", stringConverter)
+	reg.registerParamType("slug", "[a-z0-9-]+", stringConverter)
+	reg.registerParamType("mongo", "[0-9a-fA-F]{24}", stringConverter)
+	reg.registerParamType("md5", "[0-9a-fA-F]{32}", stringConverter)
+	reg.registerParamType("uuid", "[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}", stringConverter)
+	reg.registerParamType("bool", "true|false", func(value string) (interface{}, error) {
+		return strconv.ParseBool(value)
+	})
+}
+
+// registerParamType compiles and stores a named parameter type
+func (reg *registry) registerParamType(name string, pattern string, converter ParamConverter) {
+	reg.paramTypes[name] = paramType{
+		pattern:   pattern,
+		regex:     regexp.MustCompile("^(?:" + pattern + ")$"),
+		converter: converter,
+	}
+}
+
+// RegisterParamType registers a named parameter type (e.g. "uuid", "date")
+// for use as ":name(type)" in route patterns. Dispatch populates the params
+// map with converter's return value for any segment matched against it,
+// and Route.URL validates supplied values against pattern before
+// substituting them
+func (r *Router) RegisterParamType(name string, pattern string, converter ParamConverter) {
+	r.registerParamType(name, pattern, converter)
+}
@@ -0,0 +1,111 @@
+package router
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRegisterParamTypeConvertsAndDispatches(t *testing.T) {
+	r := New()
+	r.RegisterParamType("date", `[0-9]{4}-[0-9]{2}-[0-9]{2}`, func(value string) (interface{}, error) {
+		return time.Parse("2006-01-02", value)
+	})
+	r.Get("archive", "/archive/:day(date)")
+
+	route, params, _ := r.Dispatch(httptest.NewRequest("GET", "/archive/2026-07-29", nil))
+	if route == nil || route.Name != "archive" {
+		t.Fatalf("expected archive route to match, got %v", route)
+	}
+
+	day, ok := params["day"].(time.Time)
+	if !ok {
+		t.Fatalf("expected day to be converted to time.Time, got %#v", params["day"])
+	}
+	if day.Format("2006-01-02") != "2026-07-29" {
+		t.Fatalf("expected day=2026-07-29, got %s", day.Format("2006-01-02"))
+	}
+
+	if route, _, _ := r.Dispatch(httptest.NewRequest("GET", "/archive/not-a-date", nil)); route != nil {
+		t.Fatalf("expected no match for a value failing the registered pattern, got %v", route)
+	}
+}
+
+func TestRegisterParamTypeConverterErrorLeavesRouteUnmatched(t *testing.T) {
+	boom := errors.New("boom")
+
+	r := New()
+	r.RegisterParamType("evil", "[0-9]+", func(value string) (interface{}, error) {
+		return nil, boom
+	})
+	r.Get("cursed", "/cursed/:n(evil)")
+
+	route, _, _ := r.Dispatch(httptest.NewRequest("GET", "/cursed/1", nil))
+	if route != nil {
+		t.Fatalf("expected a converter error to leave the route unmatched, got %v", route)
+	}
+}
+
+func TestRouteURLValidatesTypedParams(t *testing.T) {
+	r := New()
+	route := r.Get("user", "/users/:id(int)")
+
+	url, err := route.URL("id", 42)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if url != "/users/42" {
+		t.Fatalf("expected /users/42, got %q", url)
+	}
+
+	if _, err := route.URL("id", "not-a-number"); err == nil {
+		t.Fatal("expected an error for a value that doesn't match the registered pattern")
+	}
+}
+
+func TestRouteURLBuildsAbsoluteURLWithHostAndQuery(t *testing.T) {
+	r := New()
+	route := r.Get("tenant_search", "/search").
+		Host("{tenant:[a-z]+}.example.com").
+		Schemes("https").
+		Queries("q", "{term}")
+
+	url, err := route.URL("tenant", "acme", "term", "shoes")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if url != "https://acme.example.com/search?q=shoes" {
+		t.Fatalf("expected absolute URL with host and query, got %q", url)
+	}
+}
+
+func TestRouteURLBuildsQueryStringWithoutHost(t *testing.T) {
+	r := New()
+	route := r.Get("search", "/search").Queries("q", "{term}")
+
+	url, err := route.URL("term", "shoes")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if url != "/search?q=shoes" {
+		t.Fatalf("expected /search?q=shoes, got %q", url)
+	}
+}
+
+func TestRouterURLLooksUpRouteByName(t *testing.T) {
+	r := New()
+	r.Get("user", "/users/:id(int)")
+
+	url, err := r.URL("user", "id", 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if url != "/users/7" {
+		t.Fatalf("expected /users/7, got %q", url)
+	}
+
+	if _, err := r.URL("missing"); err == nil {
+		t.Fatal("expected an error for a route name that was never registered")
+	}
+}
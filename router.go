@@ -1,15 +1,16 @@
 package router
 
 import (
+	"fmt"
 	"net/http"
-	"reflect"
 	"regexp"
-	"strconv"
 	"strings"
+	"sync"
 )
 
-// Any callable function
-type Handler interface{}
+// HandlerFunc is the signature every route handler and middleware-wrapped
+// handler must satisfy
+type HandlerFunc func(http.ResponseWriter, *http.Request)
 
 // Route holds individual route definitions
 type Route struct {
@@ -19,6 +20,10 @@ type Route struct {
 	// The name of the route
 	Name string
 
+	// The pattern as originally registered, e.g. "/users/:id(int)". This is
+	// what gets split into segments and inserted into the radix tree
+	rawPattern string
+
 	// The regex pattern with named capture groups
 	pattern string
 
@@ -26,11 +31,52 @@ type Route struct {
 	basePattern string
 
 	// The Handlers
-	Handlers []Handler
+	Handlers []HandlerFunc
+
+	// router is the Router (or Subrouter) the route was registered through;
+	// used by Subrouter to inherit its prefix, name, matchers and middleware
+	router *Router
+
+	// ancestors are the Route(s) whose Subrouter this route (or one of its
+	// own ancestors) was registered under, outermost first; used by Walk
+	ancestors []*Route
+
+	// Per-route middleware, seeded from router's at registration time and
+	// extended by Use(), composed in order around Handlers
+	middleware []func(http.Handler) http.Handler
+
+	// Host constraint, set via Host(); hostTemplate is kept for URL generation
+	hostRegex    *regexp.Regexp
+	hostTemplate string
+
+	// Scheme constraint, set via Schemes()
+	schemes []string
+
+	// Exact-match header constraints, set via Headers()
+	headers [][2]string
+
+	// Query string constraints, set via Queries()
+	queries []queryMatcher
+
+	// paramPatterns holds the anchored regex for every typed ":name(type)"
+	// segment in rawPattern, used by URL to validate substituted values
+	paramPatterns map[string]*regexp.Regexp
+
+	// compileMu guards compiled below, which is built lazily on first use
+	// by ServeHTTP-driven goroutines and so can't rely on registration-time
+	// synchronization alone
+	compileMu sync.Mutex
+
+	// compiled is the middleware-wrapped handler chain, built lazily on
+	// first use and cached so ServeHTTP never has to rebuild it
+	compiled http.Handler
 }
 
-// Router is the main struct holding all the routes and requirements for requests
-type Router struct {
+// registry holds the route state shared by a Router and every Router
+// derived from it via Subrouter/Group: the route index, the per-method
+// matching trees, and dispatch-wide settings. A Router and all of its
+// subrouters embed a pointer to the same registry
+type registry struct {
 	// An array of allowed extensions for requests
 	validExtensions []string
 
@@ -40,18 +86,79 @@ type Router struct {
 	// Holds pointers to all routes by method
 	routesByMethod map[string][]*Route
 
-	// Holds all routes by method in groups of 15 with regexes
-	regexesByMethod map[string][]*regexp.Regexp
-
-	// Holds a pointer to the matched route
-	matched *Route
+	// Holds every route in registration order, across all methods and
+	// subrouters; used by Walk
+	routesInOrder []*Route
+
+	// Holds a radix tree of routes per method, used by Dispatch to match
+	// requests in O(path length) instead of scanning every route
+	trees map[string]*node
+
+	// Tracks, per method, whether any registered route has a "*rest"-style
+	// catch-all segment. matchPath only needs to try a request path
+	// unstripped (to keep a dotted tail intact) when the method's tree
+	// could actually resolve to one, so this lets it skip that extra walk
+	// for the common case of a method with no catch-all routes at all
+	catchAllMethods map[string]bool
+
+	// Holds the named parameter types available as ":name(type)" in route
+	// patterns, registered via RegisterParamType
+	paramTypes map[string]paramType
+
+	// RedirectTrailingSlash, when true, makes Dispatch also try the path
+	// with its trailing slash added or removed before giving up
+	RedirectTrailingSlash bool
+
+	// CaseInsensitive, when true, makes Dispatch fall back to a
+	// case-insensitive lookup of static segments before giving up
+	CaseInsensitive bool
+
+	// NotFoundHandler is called when no route matches the request path.
+	// Defaults to a plain 404 response
+	NotFoundHandler http.Handler
+
+	// MethodNotAllowedHandler is called when a route matches the request
+	// path but not its method. Defaults to a plain 405 response with the
+	// Allow header set to the methods that do match
+	MethodNotAllowedHandler http.Handler
+}
 
-	// Holds group name and prefix
-	group map[string]string
+// Router is the main struct holding all the routes and requirements for
+// requests. A Router returned by New is the root of its route registry;
+// Subrouter and Group return Routers that share that same registry but
+// carry their own prefix, name, matchers and middleware, which are applied
+// to every route registered through them
+type Router struct {
+	*registry
+
+	// prefix and namePrefix are prepended to every route added through
+	// this Router, composing across nested Subrouter/Group calls
+	prefix     string
+	namePrefix string
+
+	// Middleware inherited from the parent Router plus any added via Use,
+	// seeded onto every Route registered through this Router
+	middleware []func(http.Handler) http.Handler
+
+	// Matchers inherited from the parent Router (or, for a Route's
+	// Subrouter, from that Route), seeded onto every Route registered
+	// through this Router
+	hostRegex    *regexp.Regexp
+	hostTemplate string
+	schemes      []string
+	headers      [][2]string
+	queries      []queryMatcher
+
+	// mountAncestors are the Route(s) whose Subrouter this Router (or an
+	// ancestor of it) is, outermost first; seeded onto every route added
+	// through it, and extended again by Route.Subrouter
+	mountAncestors []*Route
 }
 
 // Private method called when adding a route. This handles convenience regex syntaxes and sets up regexes with named parameters
-func newRoute(method string, name string, pattern string, handlers []Handler) (route *Route) {
+func newRoute(method string, name string, pattern string, handlers []HandlerFunc) (route *Route) {
+	rawPattern := pattern
+
 	pattern = strings.Replace(pattern, "(int)", "([0-9]+)", -1)
 	pattern = strings.Replace(pattern, "(alpha)", "([a-z]+)", -1)
 	pattern = strings.Replace(pattern, "(alphanumeric)", "([a-z0-9]+)", -1)
@@ -71,95 +178,192 @@ func newRoute(method string, name string, pattern string, handlers []Handler) (r
 		pattern = named.ReplaceAllString(pattern, "(?P<$1>[^/]+)")
 	}
 
-	route = &Route{strings.ToUpper(method), name, pattern, basePattern, handlers}
+	route = &Route{
+		method:      strings.ToUpper(method),
+		Name:        name,
+		rawPattern:  rawPattern,
+		pattern:     pattern,
+		basePattern: basePattern,
+		Handlers:    handlers,
+	}
 
 	return
 }
 
+// joinName composes two route/group name segments, matching the
+// underscore-joined naming convention routes have always used
+func joinName(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	if name == "" {
+		return prefix
+	}
+
+	return prefix + "_" + name
+}
+
 // Instantiates a new Router with basic settings
 func New() *Router {
-	router := new(Router)
-
-	router.routes = make(map[string]*Route)
+	reg := &registry{
+		validExtensions: []string{""},
+
+		routes: make(map[string]*Route),
+
+		routesByMethod: map[string][]*Route{
+			"GET":    make([]*Route, 0),
+			"POST":   make([]*Route, 0),
+			"PUT":    make([]*Route, 0),
+			"DELETE": make([]*Route, 0),
+		},
+
+		trees: map[string]*node{
+			"GET":    newNode(nodeStatic, ""),
+			"POST":   newNode(nodeStatic, ""),
+			"PUT":    newNode(nodeStatic, ""),
+			"DELETE": newNode(nodeStatic, ""),
+		},
+
+		catchAllMethods: make(map[string]bool),
+	}
 
-	// Allow requests with no extensions by default
-	router.validExtensions = append(router.validExtensions, "")
+	reg.NotFoundHandler = http.HandlerFunc(defaultNotFoundHandler)
+	reg.MethodNotAllowedHandler = http.HandlerFunc(defaultMethodNotAllowedHandler)
 
-	router.group = make(map[string]string, 2)
+	registerBuiltinParamTypes(reg)
 
-	router.routesByMethod = map[string][]*Route{
-		"GET":    make([]*Route, 0),
-		"POST":   make([]*Route, 0),
-		"PUT":    make([]*Route, 0),
-		"DELETE": make([]*Route, 0),
-	}
+	return &Router{registry: reg}
+}
 
-	router.regexesByMethod = map[string][]*regexp.Regexp{
-		"GET":    make([]*regexp.Regexp, 0),
-		"POST":   make([]*regexp.Regexp, 0),
-		"PUT":    make([]*regexp.Regexp, 0),
-		"DELETE": make([]*regexp.Regexp, 0),
-	}
+// defaultNotFoundHandler is used when no route matches the request path
+func defaultNotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "404 page not found", http.StatusNotFound)
+}
 
-	return router
+// defaultMethodNotAllowedHandler is used when a route matches the request
+// path but not its method
+func defaultMethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "405 method not allowed", http.StatusMethodNotAllowed)
 }
 
-// Generates a URL for the given Route
-func (r *Route) URL(params ...interface{}) (url string) {
-	url = r.basePattern
+// paramPatterns returns the anchored regex for every typed ":name(type)"
+// segment of pattern, resolved against paramTypes
+func paramPatterns(pattern string, paramTypes map[string]paramType) map[string]*regexp.Regexp {
+	patterns := make(map[string]*regexp.Regexp)
 
-	for key, value := range params {
-		if key%2 == 0 {
+	for _, seg := range splitPath(pattern) {
+		if !strings.HasPrefix(seg, ":") {
 			continue
 		}
 
-		name := params[key-1].(string)
+		name, pt, typed := parseParamSegment(seg, paramTypes)
+		if typed {
+			patterns[name] = pt.regex
+		}
+	}
+
+	return patterns
+}
 
-		switch v := value.(type) {
-		case int:
-			url = strings.Replace(url, ":"+name, strconv.Itoa(v), -1)
-		case string:
-			url = strings.Replace(url, ":"+name, v, -1)
+// Generates a URL for the given Route. When the route has a Host()
+// constraint, the result is an absolute URL including scheme and host;
+// otherwise it is just the path. Either way, a Queries() constraint on the
+// route is reconstructed onto the result as a query string. Returns an
+// error instead of substituting a value that doesn't match its param's
+// registered pattern
+func (r *Route) URL(params ...interface{}) (string, error) {
+	for name, value := range paramValues(params) {
+		if re, ok := r.paramPatterns[name]; ok && !re.MatchString(value) {
+			return "", fmt.Errorf("router: value %q for param %q does not match pattern %s", value, name, re.String())
 		}
 	}
 
-	return
+	url := substituteColon(r.basePattern, params)
+
+	if r.hostTemplate != "" {
+		scheme := "http"
+		if len(r.schemes) > 0 {
+			scheme = r.schemes[0]
+		}
+
+		host := substituteCurly(r.hostTemplate, params)
+
+		url = scheme + "://" + host + url
+	}
+
+	if query := buildQuery(r.queries, params); query != "" {
+		url += "?" + query
+	}
+
+	return url, nil
 }
 
 // Convenience method for adding GET routes
-func (r *Router) Get(name string, pattern string, handlers ...Handler) *Route {
+func (r *Router) Get(name string, pattern string, handlers ...HandlerFunc) *Route {
 	return r.addRoute("GET", name, pattern, handlers...)
 }
 
 // Convenience method for adding POST routes
-func (r *Router) Post(name string, pattern string, handlers ...Handler) *Route {
-	return r.addRoute("POST", name, pattern, handlers)
+func (r *Router) Post(name string, pattern string, handlers ...HandlerFunc) *Route {
+	return r.addRoute("POST", name, pattern, handlers...)
 }
 
 // Convenience method for adding PUT routes
-func (r *Router) Put(name string, pattern string, handlers ...Handler) *Route {
-	return r.addRoute("PUT", name, pattern, handlers)
+func (r *Router) Put(name string, pattern string, handlers ...HandlerFunc) *Route {
+	return r.addRoute("PUT", name, pattern, handlers...)
 }
 
 // Convenience method for adding DELETE routes
-func (r *Router) Delete(name string, pattern string, handlers ...Handler) *Route {
-	return r.addRoute("DELETE", name, pattern, handlers)
+func (r *Router) Delete(name string, pattern string, handlers ...HandlerFunc) *Route {
+	return r.addRoute("DELETE", name, pattern, handlers...)
 }
 
-// Private method for setting all routes. This adds prefixes if we are withina  group
-func (r *Router) addRoute(method string, name string, pattern string, handlers ...Handler) *Route {
-	if r.group["name"] != "" {
-		name = r.group["name"] + "_" + name
-	}
-
-	if r.group["prefix"] != "" {
-		pattern = r.group["prefix"] + pattern
-	}
+// Private method for setting all routes. This prefixes the pattern and name
+// with whatever this Router inherited from its parent (via Group or
+// Subrouter) and seeds the route's matchers and middleware the same way
+func (r *Router) addRoute(method string, name string, pattern string, handlers ...HandlerFunc) *Route {
+	name = joinName(r.namePrefix, name)
+	pattern = r.prefix + pattern
 
 	route := newRoute(method, name, pattern, handlers)
+	route.router = r
+	route.ancestors = append([]*Route(nil), r.mountAncestors...)
+	route.hostRegex = r.hostRegex
+	route.hostTemplate = r.hostTemplate
+	route.schemes = append([]string(nil), r.schemes...)
+	route.headers = append([][2]string(nil), r.headers...)
+	route.queries = append([]queryMatcher(nil), r.queries...)
+	route.middleware = append([]func(http.Handler) http.Handler(nil), r.middleware...)
 
 	r.routes[name] = route
 	r.routesByMethod[method] = append(r.routesByMethod[method], route)
+	r.routesInOrder = append(r.routesInOrder, route)
+
+	if r.trees[method] == nil {
+		r.trees[method] = newNode(nodeStatic, "")
+	}
+
+	segments := splitPath(route.rawPattern)
+	r.trees[method].insert(segments, route, r.paramTypes)
+
+	for _, seg := range segments {
+		if strings.HasPrefix(seg, "*") {
+			r.catchAllMethods[method] = true
+			break
+		}
+	}
+	route.paramPatterns = paramPatterns(route.rawPattern, r.paramTypes)
+
+	// newRoute only expands the original built-in placeholders, so swap any
+	// other registered type name (uuid, bool, RegisterParamType) left in
+	// route.pattern for its regex
+	for _, seg := range segments {
+		if name, pt, typed := parseParamSegment(seg, r.paramTypes); typed && strings.HasPrefix(seg, ":") {
+			typeName := seg[strings.Index(seg, "(")+1 : len(seg)-1]
+			route.pattern = strings.Replace(route.pattern, "(?P<"+name+">"+typeName+")", "(?P<"+name+">"+pt.pattern+")", 1)
+		}
+	}
 
 	return route
 }
@@ -178,25 +382,10 @@ func (r *Router) ValidExtensions(extensions ...string) *Router {
 	return r
 }
 
-// Compiles all regexes into groups of 15
+// Compile is kept for backwards compatibility. Routes are indexed into a
+// radix tree as they are added via Get/Post/Put/Delete, so there is nothing
+// left to compile before Dispatch can be used.
 func (r *Router) Compile() *Router {
-	for method, _ := range r.routesByMethod {
-		pattern := ""
-		for i, route := range r.routesByMethod[method] {
-			pattern += "(?P<" + route.Name + ">/)" + strings.TrimLeft(route.pattern, "/") + "|"
-
-			if i > 0 && i%15 == 0 {
-				pattern = "^(?:" + strings.TrimRight(pattern, "|") + ")$"
-				r.regexesByMethod[method] = append(r.regexesByMethod[method], regexp.MustCompile(pattern))
-
-				continue
-			}
-		}
-
-		pattern = "^(?:" + strings.TrimRight(pattern, "|") + ")$"
-		r.regexesByMethod[method] = append(r.regexesByMethod[method], regexp.MustCompile(pattern))
-	}
-
 	return r
 }
 
@@ -211,93 +400,227 @@ func (r *Router) extensionIsValid(ext string) bool {
 	return false
 }
 
-// Loops through compiled routes to see if there is a matching route
-func (r *Router) Dispatch(method string, path string) (*Route, map[string]interface{}) {
-	regex := regexp.MustCompile(`\.([^\.]+)$`)
-	params := make(map[string]interface{})
+// extRegex matches a trailing ".ext" suffix on a request path. Package-level
+// since Dispatch runs it on every request
+var extRegex = regexp.MustCompile(`\.([^\.]+)$`)
+
+// matchPath runs a path match against tree, handling extension stripping the
+// way Dispatch always has: request paths carrying a registered extension
+// (ValidExtensions) have it stripped before matching and reported back via
+// params["ext"]. A "*rest"-style catch-all route is tried first against the
+// unstripped path, since its whole point is to capture the literal
+// remainder of the path (e.g. a filename like "c.txt") rather than have a
+// "." in it misread as an extension
+func (r *Router) matchPath(tree *node, req *http.Request, method string, path string) (*Route, map[string]interface{}) {
+	pick := func(params map[string]interface{}) func([]*Route) *Route {
+		return func(routes []*Route) *Route {
+			for _, route := range routes {
+				if route.matchesRequest(req, params) {
+					return route
+				}
+			}
+
+			return nil
+		}
+	}
+
+	if r.catchAllMethods[method] && strings.Contains(path, ".") {
+		params := make(map[string]interface{})
+		if route, catchAll := tree.match(splitPath(path), params, r.CaseInsensitive, pick(params)); route != nil && catchAll {
+			params["ext"] = ""
+			return route, params
+		}
+	}
+
+	stripped := path
 	var ext string
-	var match []string
 
-	if extMatch := regex.FindString(path); extMatch != "" {
+	if extMatch := extRegex.FindString(stripped); extMatch != "" {
 		ext = strings.Replace(extMatch, ".", "", 1)
-		path = regex.ReplaceAllLiteralString(path, "")
+		stripped = extRegex.ReplaceAllLiteralString(stripped, "")
 	}
 
 	if !r.extensionIsValid(ext) {
 		return nil, nil
 	}
 
-	for _, compiled := range r.regexesByMethod[method] {
-		if match = compiled.FindStringSubmatch(path); match == nil {
-			continue
-		}
+	params := make(map[string]interface{})
+	route, _ := tree.match(splitPath(stripped), params, r.CaseInsensitive, pick(params))
+	if route == nil {
+		return nil, nil
+	}
 
-		for i, name := range compiled.SubexpNames() {
-			paramLength := len(params)
-			if i == 0 || match[i] == "" {
-				if paramLength == 0 {
-					continue
-				}
+	params["ext"] = ext
 
-				// All Params have been set. Empty matches means all params have been captured
-				break
-			}
+	return route, params
+}
+
+// Walks the radix tree for the request's method to see if there is a
+// matching route, evaluating any host/scheme/header/query matchers on the
+// candidates found at each matching leaf. splitPath trims leading and
+// trailing slashes, so a registered route already matches a request path
+// that only differs by a trailing slash; when RedirectTrailingSlash is on,
+// Dispatch reports the corrected path back as redirectPath instead of
+// resolving the route straight through, leaving the actual 3xx response to
+// ServeHTTP
+func (r *Router) Dispatch(req *http.Request) (route *Route, params map[string]interface{}, redirectPath string) {
+	origPath := req.URL.Path
+
+	tree, ok := r.trees[req.Method]
+	if !ok {
+		return nil, nil, ""
+	}
 
-			if paramLength == 0 {
-				// Capture the name and set the ext so len(params) returns 1 on next loop
-				r.matched, _ = r.FindRoute(name)
+	route, params = r.matchPath(tree, req, req.Method, origPath)
 
-				params["ext"] = ext
+	if route != nil && r.RedirectTrailingSlash {
+		wantsSlash := strings.HasSuffix(route.rawPattern, "/")
+		hasSlash := strings.HasSuffix(origPath, "/")
 
-				continue
+		if wantsSlash != hasSlash {
+			corrected := strings.TrimRight(origPath, "/")
+			if wantsSlash {
+				corrected += "/"
 			}
 
-			if intValue, err := strconv.Atoi(match[i]); err == nil {
-				params[name] = intValue
+			return nil, nil, corrected
+		}
+	}
 
-				continue
-			}
+	if route == nil && r.RedirectTrailingSlash {
+		toggled := origPath
+		if strings.HasSuffix(origPath, "/") {
+			toggled = strings.TrimRight(origPath, "/")
+		} else {
+			toggled = origPath + "/"
+		}
 
-			params[name] = match[i]
+		if toggledRoute, _ := r.matchPath(tree, req, req.Method, toggled); toggledRoute != nil {
+			return nil, nil, toggled
 		}
+	}
 
-		return r.matched, params
+	if route == nil {
+		return nil, nil, ""
 	}
 
-	return nil, nil
+	return route, params, ""
+}
+
+// allowedMethods returns the HTTP methods, in a stable order, whose tree
+// has a route matching the request's path (ignoring its own method). Used
+// to populate the Allow header on a 405. Goes through matchPath, the same
+// extension-stripping, catch-all-aware match Dispatch uses, so this never
+// reports a method as "allowed" that Dispatch would itself have rejected
+func (r *Router) allowedMethods(req *http.Request) []string {
+	methods := make([]string, 0, len(r.trees))
+
+	for _, method := range []string{"GET", "POST", "PUT", "DELETE"} {
+		tree, ok := r.trees[method]
+		if !ok {
+			continue
+		}
+
+		if route, _ := r.matchPath(tree, req, method, req.URL.Path); route != nil {
+			methods = append(methods, method)
+		}
+	}
+
+	return methods
 }
 
 // Generates a URL for a given route name
-func (r *Router) URL(name string, params ...interface{}) string {
+func (r *Router) URL(name string, params ...interface{}) (string, error) {
 	route, exists := r.FindRoute(name)
-
-	if exists {
-		return route.URL(params...)
+	if !exists {
+		return "", fmt.Errorf("router: no route named %q", name)
 	}
 
-	return ""
+	return route.URL(params...)
 }
 
-// @todo Don't use reflection
 func (r *Router) ServeHTTP(response http.ResponseWriter, request *http.Request) {
-	route, _ := r.Dispatch(request.Method, request.URL.Path)
+	route, params, redirectPath := r.Dispatch(request)
+
+	if redirectPath != "" {
+		target := *request.URL
+		target.Path = redirectPath
+
+		http.Redirect(response, request, target.String(), http.StatusMovedPermanently)
+
+		return
+	}
+
+	if route == nil {
+		if methods := r.allowedMethods(request); len(methods) > 0 {
+			response.Header().Set("Allow", strings.Join(methods, ", "))
+			r.MethodNotAllowedHandler.ServeHTTP(response, request)
+
+			return
+		}
+
+		r.NotFoundHandler.ServeHTTP(response, request)
+
+		return
+	}
+
+	request = request.WithContext(newVarsContext(request.Context(), params))
+
+	route.handler().ServeHTTP(response, request)
+}
 
-	var in = make([]reflect.Value, 2)
-	in[0] = reflect.ValueOf(response)
-	in[1] = reflect.ValueOf(request)
+// Use appends middleware to this Router, seeded onto every route added
+// through it (or a Subrouter/Group of it) afterwards. Routes already
+// registered before Use is called are unaffected
+func (r *Router) Use(mw ...func(http.Handler) http.Handler) *Router {
+	r.middleware = append(r.middleware, mw...)
 
-	for _, handler := range route.Handlers {
-		reflect.ValueOf(handler).Call(in)
+	return r
+}
+
+// Subrouter returns a Router that shares this Router's route registry but
+// inherits its own copy of the prefix, name, matchers and middleware, so
+// routes added through it nest cleanly without mutating this Router.
+// Combine it with PathPrefix/Host/Schemes/Headers/Queries to mount a group
+// of routes under a shared constraint without registering a dispatchable
+// parent route the way Route.Subrouter requires
+func (r *Router) Subrouter() *Router {
+	return &Router{
+		registry:       r.registry,
+		prefix:         r.prefix,
+		namePrefix:     r.namePrefix,
+		middleware:     append([]func(http.Handler) http.Handler(nil), r.middleware...),
+		hostRegex:      r.hostRegex,
+		hostTemplate:   r.hostTemplate,
+		schemes:        append([]string(nil), r.schemes...),
+		headers:        append([][2]string(nil), r.headers...),
+		queries:        append([]queryMatcher(nil), r.queries...),
+		mountAncestors: append([]*Route(nil), r.mountAncestors...),
 	}
 }
 
-// @todo Add support for handlers
-func (r *Router) Group(prefix string, name string, fn func(*Router), handlers ...Handler) {
-	r.group["prefix"] = strings.TrimRight(prefix, "/")
-	r.group["name"] = strings.TrimRight(name, "_")
+// Walk calls fn once for every registered route, in registration order,
+// including routes added through subrouters and groups. ancestors holds the
+// Route(s) whose Subrouter the route was nested under, outermost first.
+// Walk stops and returns the first error fn returns
+func (r *Router) Walk(fn func(route *Route, router *Router, ancestors []*Route) error) error {
+	for _, route := range r.routesInOrder {
+		if err := fn(route, route.router, route.ancestors); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
-	fn(r)
+// Group registers routes added inside fn under a shared prefix and name,
+// with the given middleware applied on top of this Router's own. Unlike
+// the prefix alone, this composes across nested Group/Subrouter calls
+func (r *Router) Group(prefix string, name string, fn func(*Router), mw ...func(http.Handler) http.Handler) {
+	sub := r.Subrouter()
+	sub.prefix = r.prefix + strings.TrimRight(prefix, "/")
+	sub.namePrefix = joinName(r.namePrefix, strings.TrimRight(name, "_"))
+	sub.middleware = append(sub.middleware, mw...)
 
-	r.group["prefix"] = ""
-	r.group["name"] = ""
+	fn(sub)
 }
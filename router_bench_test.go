@@ -0,0 +1,32 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// BenchmarkDispatch measures Dispatch against a router holding a few
+// hundred registered routes, the scale the radix tree rewrite (replacing
+// the old linear regex bundle scan) was meant to keep roughly O(path
+// length) instead of O(route count). The old alternation-based matcher
+// was deleted as part of the rewrite, so there's nothing left in this
+// package to run a before/after comparison against; checking out the
+// commit prior to the rewrite and running this same shape of benchmark
+// there is the only way to reproduce the improvement this was written to
+// demonstrate
+func BenchmarkDispatch(b *testing.B) {
+	r := New()
+
+	for i := 0; i < 300; i++ {
+		r.Get(fmt.Sprintf("route_%d", i), fmt.Sprintf("/resource%d/:id", i), func(w http.ResponseWriter, req *http.Request) {})
+	}
+
+	req := httptest.NewRequest("GET", "/resource299/42", nil)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Dispatch(req)
+	}
+}
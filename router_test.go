@@ -0,0 +1,127 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDispatchTypedAndUntypedParams(t *testing.T) {
+	r := New()
+	r.Get("typed", "/users/:id(int)")
+	r.Get("untyped", "/posts/:slug")
+
+	route, params, redirectPath := r.Dispatch(httptest.NewRequest("GET", "/users/42", nil))
+	if route == nil || route.Name != "typed" {
+		t.Fatalf("expected typed route, got %v", route)
+	}
+	if redirectPath != "" {
+		t.Fatalf("expected no redirect, got %q", redirectPath)
+	}
+	if params["id"] != 42 {
+		t.Fatalf("expected id=42 (int), got %#v", params["id"])
+	}
+
+	route, params, _ = r.Dispatch(httptest.NewRequest("GET", "/posts/hello-world", nil))
+	if route == nil || route.Name != "untyped" {
+		t.Fatalf("expected untyped route, got %v", route)
+	}
+	if params["slug"] != "hello-world" {
+		t.Fatalf("expected slug=hello-world, got %#v", params["slug"])
+	}
+}
+
+func TestDispatchCatchAllKeepsDottedTail(t *testing.T) {
+	r := New()
+	r.Get("files", "/files/*rest")
+
+	route, params, _ := r.Dispatch(httptest.NewRequest("GET", "/files/a/b/c.txt", nil))
+	if route == nil {
+		t.Fatal("expected catch-all route to match a dotted tail")
+	}
+	if params["rest"] != "a/b/c.txt" {
+		t.Fatalf("expected rest=a/b/c.txt, got %#v", params["rest"])
+	}
+}
+
+func TestDispatchTrailingSlashRedirect(t *testing.T) {
+	r := New()
+	r.RedirectTrailingSlash = true
+	r.Get("users", "/users/")
+
+	route, _, redirectPath := r.Dispatch(httptest.NewRequest("GET", "/users", nil))
+	if route != nil {
+		t.Fatalf("expected no route resolved directly, got %v", route)
+	}
+	if redirectPath != "/users/" {
+		t.Fatalf("expected redirect to /users/, got %q", redirectPath)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/users", nil))
+	if w.Code != http.StatusMovedPermanently {
+		t.Fatalf("expected 301, got %d", w.Code)
+	}
+	if w.Header().Get("Location") != "/users/" {
+		t.Fatalf("expected Location: /users/, got %q", w.Header().Get("Location"))
+	}
+}
+
+func TestDispatchCaseInsensitive(t *testing.T) {
+	r := New()
+	r.CaseInsensitive = true
+	r.Get("users", "/Users/:id")
+
+	route, params, _ := r.Dispatch(httptest.NewRequest("GET", "/users/7", nil))
+	if route == nil || route.Name != "users" {
+		t.Fatalf("expected case-insensitive match, got %v", route)
+	}
+	if params["id"] != 7 {
+		t.Fatalf("expected id=7, got %#v", params["id"])
+	}
+}
+
+func TestServeHTTPNotFound(t *testing.T) {
+	r := New()
+	r.Get("users", "/users/:id")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/nope", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestServeHTTPMethodNotAllowed(t *testing.T) {
+	r := New()
+	r.Get("users", "/users/:id")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/users/1", nil))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET" {
+		t.Fatalf("expected Allow: GET, got %q", allow)
+	}
+}
+
+// TestServeHTTPMethodNotAllowedIgnoresUnvalidatedExtension is a regression
+// test for allowedMethods matching the raw request path directly: without
+// going through the same extension-stripping, catch-all-aware match
+// Dispatch uses, it could report a method as "allowed" for a request
+// Dispatch itself 404s (an invalid, unregistered extension), producing a
+// misleading 405 instead of the 404 Dispatch actually resolved to
+func TestServeHTTPMethodNotAllowedIgnoresUnvalidatedExtension(t *testing.T) {
+	r := New()
+	r.Get("users", "/users/:id")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/users/1.json", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unregistered extension, got %d", w.Code)
+	}
+}
@@ -0,0 +1,52 @@
+package router
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteSubrouterInheritsMatchersAndPrefix(t *testing.T) {
+	r := New()
+	api := r.Get("api", "/api/").Headers("X-API-Key", "secret")
+	sub := api.Subrouter()
+	sub.Get("settings", "/settings")
+
+	route, found := r.FindRoute("api_settings")
+	if !found {
+		t.Fatal("expected api_settings to be registered")
+	}
+
+	if route.BasePattern() != "/api/settings" {
+		t.Fatalf("expected a single slash joining the parent's trailing slash and the child's pattern, got %q", route.BasePattern())
+	}
+
+	req := httptest.NewRequest("GET", "/api/settings", nil)
+	if route, _, _ := r.Dispatch(req); route != nil {
+		t.Fatalf("expected no match without the inherited header, got %v", route)
+	}
+
+	req.Header.Set("X-API-Key", "secret")
+	if route, _, _ := r.Dispatch(req); route == nil || route.Name != "api_settings" {
+		t.Fatalf("expected the child route to inherit the parent's header matcher, got %v", route)
+	}
+}
+
+func TestRouterPathPrefixMountHasNoDispatchableParent(t *testing.T) {
+	r := New()
+	api := r.Subrouter().PathPrefix("/api").Headers("X-API-Key", "secret")
+	api.Get("settings", "/settings")
+
+	req := httptest.NewRequest("GET", "/api/settings", nil)
+	req.Header.Set("X-API-Key", "secret")
+	if route, _, _ := r.Dispatch(req); route == nil || route.Name != "settings" {
+		t.Fatalf("expected the mounted route to inherit the prefix and header matcher, got %v", route)
+	}
+
+	// Unlike Route.Subrouter, PathPrefix never registers a route of its
+	// own, so a request for the mount point itself has nothing to match
+	req = httptest.NewRequest("GET", "/api", nil)
+	req.Header.Set("X-API-Key", "secret")
+	if route, _, _ := r.Dispatch(req); route != nil {
+		t.Fatalf("expected no dispatchable route at the mount point itself, got %v", route)
+	}
+}
@@ -0,0 +1,239 @@
+package router
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// nodeKind identifies what a single path segment in the radix tree matches
+// against.
+type nodeKind int
+
+const (
+	// nodeStatic matches a literal segment, e.g. "users"
+	nodeStatic nodeKind = iota
+
+	// nodeParam matches any non-empty segment and captures it under a name,
+	// e.g. ":id"
+	nodeParam
+
+	// nodeRegexParam matches a segment against a precompiled per-segment
+	// regex and captures it under a name, e.g. ":id(int)"
+	nodeRegexParam
+
+	// nodeCatchAll matches all remaining segments, e.g. "*rest"
+	nodeCatchAll
+)
+
+// node is a single segment of a radix tree used to match request paths
+// without scanning every registered route.
+type node struct {
+	kind nodeKind
+
+	// segment is the literal text for a static node, or the parameter name
+	// for a param/regexParam/catchAll node
+	segment string
+
+	// regex matches the raw segment text for a typed parameter node
+	regex *regexp.Regexp
+
+	// converter turns a regexParam node's matched text into the Go value
+	// stored in params, per the registered paramType
+	converter ParamConverter
+
+	// routes are the routes that terminate at this node. More than one
+	// route can share a path when they are distinguished by other request
+	// matchers (host, scheme, headers, query); see Dispatch
+	routes []*Route
+
+	staticChildren map[string]*node
+	paramChild     *node
+	regexChildren  []*node
+	catchAllChild  *node
+}
+
+func newNode(kind nodeKind, segment string) *node {
+	return &node{kind: kind, segment: segment, staticChildren: make(map[string]*node)}
+}
+
+// splitPath breaks a route pattern or request path into its segments,
+// ignoring leading/trailing slashes.
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+
+	if path == "" {
+		return []string{}
+	}
+
+	return strings.Split(path, "/")
+}
+
+// parseParamSegment splits a ":name" or ":name(type)" segment into its
+// parameter name and the paramType to match it against, if any. ok is
+// false (and pt empty) for a plain ":name" segment. A parenthesized segment
+// that isn't a registered type name is treated as a raw regex, matching
+// the historical ":name(pattern)" syntax.
+func parseParamSegment(seg string, paramTypes map[string]paramType) (name string, pt paramType, ok bool) {
+	seg = strings.TrimPrefix(seg, ":")
+
+	open := strings.Index(seg, "(")
+	if open == -1 || !strings.HasSuffix(seg, ")") {
+		return seg, paramType{}, false
+	}
+
+	name = seg[:open]
+	typeOrPattern := seg[open+1 : len(seg)-1]
+
+	if registered, found := paramTypes[typeOrPattern]; found {
+		return name, registered, true
+	}
+
+	return name, paramType{
+		pattern:   typeOrPattern,
+		regex:     regexp.MustCompile("^(?:" + typeOrPattern + ")$"),
+		converter: stringConverter,
+	}, true
+}
+
+// insert adds route into the tree along the given path segments, resolving
+// any ":name(type)" segments against paramTypes.
+func (n *node) insert(segments []string, route *Route, paramTypes map[string]paramType) {
+	if len(segments) == 0 {
+		n.routes = append(n.routes, route)
+		return
+	}
+
+	seg := segments[0]
+	rest := segments[1:]
+
+	switch {
+	case strings.HasPrefix(seg, "*"):
+		if n.catchAllChild == nil {
+			n.catchAllChild = newNode(nodeCatchAll, seg[1:])
+		}
+		n.catchAllChild.routes = append(n.catchAllChild.routes, route)
+
+	case strings.HasPrefix(seg, ":"):
+		name, pt, typed := parseParamSegment(seg, paramTypes)
+
+		if !typed {
+			if n.paramChild == nil {
+				n.paramChild = newNode(nodeParam, name)
+			}
+			n.paramChild.insert(rest, route, paramTypes)
+			return
+		}
+
+		for _, child := range n.regexChildren {
+			if child.segment == name && child.regex == pt.regex {
+				child.insert(rest, route, paramTypes)
+				return
+			}
+		}
+
+		child := newNode(nodeRegexParam, name)
+		child.regex = pt.regex
+		child.converter = pt.converter
+		n.regexChildren = append(n.regexChildren, child)
+		child.insert(rest, route, paramTypes)
+
+	default:
+		child, ok := n.staticChildren[seg]
+		if !ok {
+			child = newNode(nodeStatic, seg)
+			n.staticChildren[seg] = child
+		}
+		child.insert(rest, route, paramTypes)
+	}
+}
+
+// assignParam records a captured path parameter for a plain, untyped
+// ":name" segment, converting it to an int when it looks numeric so
+// existing consumers keep seeing typed values without having to register
+// a param type just for that
+func assignParam(params map[string]interface{}, name string, value string) {
+	if intValue, err := strconv.Atoi(value); err == nil {
+		params[name] = intValue
+		return
+	}
+
+	params[name] = value
+}
+
+// match walks the tree for the given path segments, collecting captured
+// parameters into params. pick is called with every candidate route found
+// at a matching leaf and must return the one to use (or nil to keep
+// searching other branches), which lets callers layer additional request
+// matchers (host, scheme, headers, query) on top of the path match.
+// catchAll reports whether the returned route was reached through a
+// "*rest"-style node, so callers can tell a route whose terminal segment
+// swallows the rest of the path (and so shouldn't have it reinterpreted,
+// e.g. extension-stripped) from an ordinary one
+func (n *node) match(segments []string, params map[string]interface{}, caseInsensitive bool, pick func([]*Route) *Route) (route *Route, catchAll bool) {
+	if len(segments) == 0 {
+		if n.routes != nil {
+			if route := pick(n.routes); route != nil {
+				return route, false
+			}
+		}
+	} else {
+		seg := segments[0]
+		rest := segments[1:]
+
+		if child, ok := n.staticChildren[seg]; ok {
+			if route, catchAll := child.match(rest, params, caseInsensitive, pick); route != nil {
+				return route, catchAll
+			}
+		} else if caseInsensitive {
+			lower := strings.ToLower(seg)
+			for text, child := range n.staticChildren {
+				if strings.ToLower(text) != lower {
+					continue
+				}
+				if route, catchAll := child.match(rest, params, caseInsensitive, pick); route != nil {
+					return route, catchAll
+				}
+				break
+			}
+		}
+
+		for _, child := range n.regexChildren {
+			if !child.regex.MatchString(seg) {
+				continue
+			}
+
+			value, err := child.converter(seg)
+			if err != nil {
+				continue
+			}
+			params[child.segment] = value
+
+			if route, catchAll := child.match(rest, params, caseInsensitive, pick); route != nil {
+				return route, catchAll
+			}
+
+			delete(params, child.segment)
+		}
+
+		if n.paramChild != nil {
+			assignParam(params, n.paramChild.segment, seg)
+
+			if route, catchAll := n.paramChild.match(rest, params, caseInsensitive, pick); route != nil {
+				return route, catchAll
+			}
+
+			delete(params, n.paramChild.segment)
+		}
+	}
+
+	if n.catchAllChild != nil && n.catchAllChild.routes != nil {
+		params[n.catchAllChild.segment] = strings.Join(segments, "/")
+		if route := pick(n.catchAllChild.routes); route != nil {
+			return route, true
+		}
+		delete(params, n.catchAllChild.segment)
+	}
+
+	return nil, false
+}
@@ -0,0 +1,89 @@
+package router
+
+import "testing"
+
+func TestWalkVisitsRoutesInRegistrationOrderWithAncestors(t *testing.T) {
+	r := New()
+	r.Get("home", "/")
+
+	r.Group("/admin", "admin", func(sub *Router) {
+		sub.Get("dashboard", "/dashboard")
+
+		admin := sub.Get("users", "/users")
+		admin.Subrouter().Get("show", "/:id")
+	})
+
+	type visit struct {
+		name      string
+		method    string
+		pattern   string
+		ancestors []string
+	}
+
+	var got []visit
+	err := r.Walk(func(route *Route, router *Router, ancestors []*Route) error {
+		names := make([]string, len(ancestors))
+		for i, a := range ancestors {
+			names[i] = a.Name
+		}
+		got = append(got, visit{route.Name, route.Method(), route.BasePattern(), names})
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error from Walk: %v", err)
+	}
+
+	expected := []visit{
+		{"home", "GET", "/", nil},
+		{"admin_dashboard", "GET", "/admin/dashboard", nil},
+		{"admin_users", "GET", "/admin/users", nil},
+		{"admin_users_show", "GET", "/admin/users/:id", []string{"admin_users"}},
+	}
+
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d routes, got %d: %+v", len(expected), len(got), got)
+	}
+
+	for i, want := range expected {
+		have := got[i]
+		if have.name != want.name || have.method != want.method || have.pattern != want.pattern {
+			t.Fatalf("route %d: expected %+v, got %+v", i, want, have)
+		}
+		if len(have.ancestors) != len(want.ancestors) {
+			t.Fatalf("route %d: expected ancestors %v, got %v", i, want.ancestors, have.ancestors)
+		}
+		for j, name := range want.ancestors {
+			if have.ancestors[j] != name {
+				t.Fatalf("route %d: expected ancestors %v, got %v", i, want.ancestors, have.ancestors)
+			}
+		}
+	}
+}
+
+func TestWalkStopsOnFirstError(t *testing.T) {
+	r := New()
+	r.Get("one", "/one")
+	r.Get("two", "/two")
+
+	visited := 0
+	boom := errFromWalk("boom")
+
+	err := r.Walk(func(route *Route, router *Router, ancestors []*Route) error {
+		visited++
+		return boom
+	})
+
+	if err != boom {
+		t.Fatalf("expected Walk to return the fn error, got %v", err)
+	}
+	if visited != 1 {
+		t.Fatalf("expected Walk to stop after the first route, visited %d", visited)
+	}
+}
+
+// errFromWalk is a trivial error type local to this test, just so the
+// assertion above can compare it with ==
+type errFromWalk string
+
+func (e errFromWalk) Error() string { return string(e) }